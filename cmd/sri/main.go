@@ -0,0 +1,200 @@
+// Copyright 2023 Philipp Stephani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Binary sri computes secure hashes in Subresource Integrity format for files or URLs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/phst/sri"
+	_ "github.com/phst/sri/extra"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "scan" {
+		if !cmdScan(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	flag.Usage = usage
+	var hashName string
+	flag.StringVar(&hashName, "hash", "sha384", fmt.Sprintf("comma-separated list of hash functions to use (one of %v)", sri.Algorithms()))
+	var verify bool
+	flag.BoolVar(&verify, "verify", false, `verify mode: treat positional arguments as "path-or-url=sri" entries`)
+	var verifyFrom string
+	flag.StringVar(&verifyFrom, "verify-from", "", "verify mode: read entries from the given manifest file, in the same tab-separated format sri itself prints (\"-\" means standard input)")
+	var cacheDir string
+	flag.StringVar(&cacheDir, "cache", os.Getenv("SRI_CACHE"), "verify mode: content-addressable cache directory for downloaded URLs, keyed by hash (also read from the SRI_CACHE environment variable)")
+	var jobs int
+	flag.IntVar(&jobs, "j", runtime.NumCPU(), "maximum number of files/URLs to process concurrently")
+	var progress bool
+	flag.BoolVar(&progress, "progress", false, "report per-file progress to standard error (ignored unless standard error is a terminal)")
+	flag.Parse()
+
+	if verify || verifyFrom != "" {
+		ok := runVerify(flag.Args(), verifyFrom, cacheDir)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	hashNames := strings.Split(hashName, ",")
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	results := runAll(files, hashNames, jobs, progress)
+
+	ok := true
+	suffix := len(files) > 1
+	for i, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "sri: %s: %s\n", files[i], r.err)
+			ok = false
+			continue
+		}
+		tokens := make([]string, len(r.hashes))
+		for j, h := range r.hashes {
+			tokens[j] = h.String()
+		}
+		s := strings.Join(tokens, " ")
+		if suffix {
+			s += "\t" + files[i]
+		}
+		if _, err := fmt.Fprintln(os.Stdout, s); err != nil {
+			fmt.Fprintf(os.Stderr, "sri: %s: %s", files[i], err)
+			ok = false
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// runAll hashes every file in files, using at most jobs worker goroutines,
+// and returns one result per file in the same order as files regardless of
+// which goroutine finishes first.
+func runAll(files []string, hashNames []string, jobs int, progress bool) []result {
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > len(files) {
+		jobs = len(files)
+	}
+	var reporter *progressReporter
+	if progress && isTerminal(os.Stderr) {
+		reporter = newProgressReporter(os.Stderr)
+		defer reporter.done()
+	}
+	results := make([]result, len(files))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = run(files[i], hashNames, reporter)
+			}
+		}()
+	}
+	for i := range files {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return results
+}
+
+func usage() {
+	os.Stderr.WriteString(`sri [options] [files and URLs...]
+
+Computes cryptographic hashes for each of the given files or HTTP URLs.
+For each file/URL, prints the hashes requested via -hash in Subresource
+Integrity format separated by spaces (the format accepted by the HTML
+"integrity" attribute for fallback), followed by a tab character, the
+filename/URL and a newline.
+If no files are given, reads standard input.
+A file named "-" is also interpreted to mean standard input.
+If zero or one positional arguments are given,
+print only the hash(es) without a filename.
+Output is always printed in the same order as the arguments, regardless
+of the order in which the (at most -j concurrent) files finish hashing.
+
+sri -verify [path-or-url=sri...]
+sri -verify-from FILE
+
+Verifies that files or URLs match expected Subresource Integrity hashes
+instead of printing hashes, and exits with a non-zero status if any entry
+does not match, printing "OK" or "FAILED" for each entry in the style of
+sha256sum -c.
+
+If -cache is given, downloaded URLs are stored in and served from a
+content-addressable cache directory keyed by their expected hash, so a
+repeated -verify of the same URL need not hit the network.
+
+sri scan [options] [files and URLs...]
+
+Finds HTML script and stylesheet references missing an "integrity"
+attribute; run "sri scan -help" for details.
+`)
+	flag.PrintDefaults()
+}
+
+func run(f string, hashNames []string, reporter *progressReporter) result {
+	r, err := sri.Open(f)
+	if err != nil {
+		return result{err: err}
+	}
+	defer r.Close()
+	ws := make([]*sri.Writer, len(hashNames))
+	writers := make([]io.Writer, len(hashNames))
+	for i, name := range hashNames {
+		w, err := sri.NewWriter(name)
+		if err != nil {
+			return result{err: err}
+		}
+		ws[i] = w
+		writers[i] = w
+	}
+	var reader io.Reader = r
+	if reporter != nil {
+		reader = io.TeeReader(r, reporter.writer(f))
+		defer reporter.finish(f)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), reader); err != nil {
+		return result{err: err}
+	}
+	hashes := make([]sri.Hash, len(ws))
+	for i, w := range ws {
+		hashes[i] = w.Sum()
+	}
+	return result{hashes: hashes}
+}
+
+type result struct {
+	err    error
+	hashes []sri.Hash
+}
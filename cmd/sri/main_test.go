@@ -0,0 +1,106 @@
+// Copyright 2023 Philipp Stephani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/phst/sri"
+)
+
+// TestRunAllPreservesOrder fans work across multiple workers with
+// artificially skewed latencies (the first argument is the slowest to
+// finish) and checks that results still come back in argument order, not
+// completion order.
+func TestRunAllPreservesOrder(t *testing.T) {
+	const n = 5
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var i int
+		fmt.Sscanf(r.URL.Path, "/%d", &i)
+		time.Sleep(time.Duration(n-i) * 5 * time.Millisecond)
+		fmt.Fprintf(w, "content-%d", i)
+	}))
+	defer srv.Close()
+
+	var files []string
+	for i := 0; i < n; i++ {
+		files = append(files, fmt.Sprintf("%s/%d", srv.URL, i))
+	}
+
+	results := runAll(files, []string{"sha256"}, n, false)
+	if len(results) != len(files) {
+		t.Fatalf("len(results) = %d, want %d", len(results), len(files))
+	}
+	for i := range files {
+		w, err := sri.NewWriter("sha256")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(fmt.Sprintf("content-%d", i)))
+		want := w.Sum()
+		if results[i].err != nil {
+			t.Errorf("result[%d]: unexpected error %v", i, results[i].err)
+			continue
+		}
+		if len(results[i].hashes) != 1 || !results[i].hashes[0].Equal(want) {
+			t.Errorf("result[%d] = %v, want %v", i, results[i].hashes, want)
+		}
+	}
+}
+
+// TestVerifyCacheRoundTrip checks that -cache stores a verified download and
+// that a subsequent verification is served from the cache without hitting
+// the network again.
+func TestVerifyCacheRoundTrip(t *testing.T) {
+	const body = "cached payload"
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	w, err := sri.NewWriter("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(body))
+	h := w.Sum()
+
+	cacheDir := t.TempDir()
+	entry := srv.URL + "=" + h.String()
+
+	if !runVerify([]string{entry}, "", cacheDir) {
+		t.Fatal("first runVerify reported failure")
+	}
+	if requests != 1 {
+		t.Fatalf("requests after first verify = %d, want 1", requests)
+	}
+	if _, err := os.Stat(h.CachePath(cacheDir)); err != nil {
+		t.Fatalf("cache file not written: %v", err)
+	}
+
+	if !runVerify([]string{entry}, "", cacheDir) {
+		t.Fatal("second runVerify reported failure")
+	}
+	if requests != 1 {
+		t.Fatalf("requests after second verify = %d, want still 1 (should be served from cache)", requests)
+	}
+}
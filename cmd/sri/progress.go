@@ -0,0 +1,131 @@
+// Copyright 2023 Philipp Stephani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// progressReporter aggregates byte counters for any number of concurrently
+// hashed files into a single status line, so that progress from multiple
+// workers doesn't interleave into unreadable noise on a shared terminal
+// line. Only in-flight files are tracked by name; finished files are folded
+// into running totals instead of being kept around forever, so the status
+// line stays bounded even across a batch of thousands of files.
+type progressReporter struct {
+	mu        sync.Mutex
+	out       io.Writer
+	counts    map[string]int64
+	order     []string
+	doneFiles int64
+	doneBytes int64
+	lastLen   int
+}
+
+func newProgressReporter(out io.Writer) *progressReporter {
+	return &progressReporter{out: out, counts: make(map[string]int64)}
+}
+
+// writer returns an io.Writer that reports bytes written to it as progress
+// for name, meant to be fed via io.TeeReader alongside the real hashing.
+func (p *progressReporter) writer(name string) io.Writer {
+	return &progressFile{reporter: p, name: name}
+}
+
+func (p *progressReporter) update(name string, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.counts[name]; !ok {
+		p.order = append(p.order, name)
+	}
+	p.counts[name] += int64(n)
+	p.render()
+}
+
+// finish removes name from the in-flight set and folds its final byte count
+// into the running totals, so that a completed file no longer takes up
+// space in the status line.
+func (p *progressReporter) finish(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n, ok := p.counts[name]
+	if !ok {
+		return
+	}
+	delete(p.counts, name)
+	for i, nm := range p.order {
+		if nm == name {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+	p.doneFiles++
+	p.doneBytes += n
+	p.render()
+}
+
+// render rewrites the status line from the current in-flight files plus the
+// running totals for already-finished ones. p.mu must be held.
+func (p *progressReporter) render() {
+	parts := make([]string, 0, len(p.order)+1)
+	if p.doneFiles > 0 {
+		parts = append(parts, fmt.Sprintf("%d done (%d bytes)", p.doneFiles, p.doneBytes))
+	}
+	for _, nm := range p.order {
+		parts = append(parts, fmt.Sprintf("%s: %d bytes", nm, p.counts[nm]))
+	}
+	line := strings.Join(parts, "  ")
+	pad := p.lastLen - len(line)
+	if pad < 0 {
+		pad = 0
+	}
+	fmt.Fprintf(p.out, "\r%s%s", line, strings.Repeat(" ", pad))
+	p.lastLen = len(line)
+}
+
+// done ends the status line with a trailing newline.
+func (p *progressReporter) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.order) > 0 || p.doneFiles > 0 {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// progressFile reports bytes written to it as progress for a single named
+// file via its reporter.
+type progressFile struct {
+	reporter *progressReporter
+	name     string
+}
+
+func (p *progressFile) Write(b []byte) (int, error) {
+	p.reporter.update(p.name, len(b))
+	return len(b), nil
+}
+
+// isTerminal reports whether f refers to a terminal, so progress reporting
+// doesn't pollute output piped to another program.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
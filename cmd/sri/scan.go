@@ -0,0 +1,273 @@
+// Copyright 2023 Philipp Stephani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/phst/sri"
+)
+
+// cmdScan implements the "sri scan" subcommand: it finds <script src=...>
+// and <link rel="stylesheet" href=...> elements without an "integrity"
+// attribute, computes the missing hash, and either reports or inserts it.
+func cmdScan(args []string) bool {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	var hashName string
+	fs.StringVar(&hashName, "hash", "sha384", fmt.Sprintf("hash function to use (one of %v)", sri.Algorithms()))
+	var rewrite bool
+	fs.BoolVar(&rewrite, "rewrite", false, "rewrite local HTML files in place instead of printing a report")
+	var root string
+	fs.StringVar(&root, "root", "", "site root that root-relative references (\"/assets/app.js\") are resolved against, for local HTML files (default: the current directory)")
+	fs.Usage = func() { scanUsage(fs) }
+	fs.Parse(args)
+
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sri: %s\n", err)
+			return false
+		}
+		root = wd
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	ok := true
+	for _, f := range files {
+		if err := scanFile(f, hashName, rewrite, root); err != nil {
+			fmt.Fprintf(os.Stderr, "sri: %s: %s\n", f, err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func scanUsage(fs *flag.FlagSet) {
+	os.Stderr.WriteString(`sri scan [options] [files and URLs...]
+
+Parses each HTML file or URL, finds <script src=...> and
+<link rel="stylesheet" href=...> elements missing an "integrity"
+attribute, fetches the referenced asset, and computes its hash.
+By default, prints one report line per missing attribute. With -rewrite,
+instead inserts the "integrity" attribute (and "crossorigin=\"anonymous\""
+for cross-origin assets) and writes the result back to the file; for
+standard input or URLs, the rewritten HTML is printed to standard output
+instead.
+References starting with "/" in a local HTML file are resolved against
+-root rather than the file's own directory, matching how browsers
+resolve root-relative URLs.
+`)
+	fs.PrintDefaults()
+}
+
+// asset is an HTML element referencing an external resource that is
+// missing an "integrity" attribute.
+type asset struct {
+	node *html.Node
+	attr string // "src" or "href"
+	ref  string // the attribute's literal value, possibly relative
+}
+
+func scanFile(f, hashName string, rewrite bool, root string) error {
+	r, err := sri.Open(f)
+	if err != nil {
+		return err
+	}
+	doc, err := html.Parse(r)
+	r.Close()
+	if err != nil {
+		return err
+	}
+
+	assets := findAssets(doc)
+	ok := true
+	for _, a := range assets {
+		target := resolveRef(f, a.ref, root)
+		h, err := hashAsset(target, hashName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sri: %s: %s: %s\n", f, a.ref, err)
+			ok = false
+			continue
+		}
+		if !rewrite {
+			fmt.Printf("%s: %s missing integrity for %s: %s\n", f, a.node.Data, a.ref, h)
+			continue
+		}
+		setAttr(a.node, "integrity", h.String())
+		if crossOrigin(f, target) {
+			setAttr(a.node, "crossorigin", "anonymous")
+		}
+	}
+	if !ok {
+		return fmt.Errorf("not all referenced assets could be hashed")
+	}
+	if !rewrite {
+		return nil
+	}
+	if len(assets) == 0 && f != "-" && !isURL(f) {
+		// Nothing changed; leave the local file untouched.
+		return nil
+	}
+	return writeBack(f, doc)
+}
+
+// findAssets walks the HTML tree rooted at doc and returns every script or
+// stylesheet link that is missing an "integrity" attribute.
+func findAssets(doc *html.Node) []asset {
+	var assets []asset
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script":
+				if src, ok := attrVal(n, "src"); ok && !hasAttr(n, "integrity") {
+					assets = append(assets, asset{node: n, attr: "src", ref: src})
+				}
+			case "link":
+				if rel, _ := attrVal(n, "rel"); strings.EqualFold(rel, "stylesheet") {
+					if href, ok := attrVal(n, "href"); ok && !hasAttr(n, "integrity") {
+						assets = append(assets, asset{node: n, attr: "href", ref: href})
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return assets
+}
+
+func hashAsset(target, hashName string) (sri.Hash, error) {
+	r, err := sri.Open(target)
+	if err != nil {
+		return sri.Hash{}, err
+	}
+	defer r.Close()
+	w, err := sri.NewWriter(hashName)
+	if err != nil {
+		return sri.Hash{}, err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return sri.Hash{}, err
+	}
+	return w.Sum(), nil
+}
+
+// resolveRef resolves an asset reference found in doc (a file path, "-", or
+// URL) against the document it was found in. Root-relative references
+// ("/assets/app.js") are resolved against root instead of doc's own
+// directory, matching how browsers resolve them against the site root
+// rather than the referring page's path.
+func resolveRef(doc, ref, root string) string {
+	if isURL(ref) {
+		return ref
+	}
+	if strings.HasPrefix(ref, "//") {
+		scheme := "https"
+		if isURL(doc) {
+			if u, err := url.Parse(doc); err == nil && u.Scheme != "" {
+				scheme = u.Scheme
+			}
+		}
+		return scheme + ":" + ref
+	}
+	if isURL(doc) {
+		base, err := url.Parse(doc)
+		if err != nil {
+			return ref
+		}
+		rel, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return base.ResolveReference(rel).String()
+	}
+	if doc == "-" {
+		return ref
+	}
+	if strings.HasPrefix(ref, "/") {
+		return filepath.Join(root, ref)
+	}
+	return filepath.Join(filepath.Dir(doc), ref)
+}
+
+// crossOrigin reports whether target has a different origin (scheme and
+// host) than doc.
+func crossOrigin(doc, target string) bool {
+	if !isURL(target) {
+		return false
+	}
+	if !isURL(doc) {
+		return true
+	}
+	d, err1 := url.Parse(doc)
+	t, err2 := url.Parse(target)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	return d.Scheme != t.Scheme || d.Host != t.Host
+}
+
+func attrVal(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	_, ok := attrVal(n, key)
+	return ok
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// writeBack renders doc and writes it back to f: in place if f names a local
+// file, or to standard output if f is "-" or a URL.
+func writeBack(f string, doc *html.Node) error {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return err
+	}
+	if f == "-" || isURL(f) {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(f, buf.Bytes(), 0o644)
+}
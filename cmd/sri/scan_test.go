@@ -0,0 +1,36 @@
+// Copyright 2023 Philipp Stephani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestResolveRef(t *testing.T) {
+	for _, tc := range []struct {
+		doc, ref, root, want string
+	}{
+		{"/site/blog/post1/index.html", "app.js", "/site", "/site/blog/post1/app.js"},
+		{"/site/blog/post1/index.html", "/assets/app.js", "/site", "/site/assets/app.js"},
+		{"https://example.com/blog/post1/index.html", "/assets/app.js", "/site", "https://example.com/assets/app.js"},
+		{"/site/blog/post1/index.html", "//cdn.example.com/app.js", "/site", "https://cdn.example.com/app.js"},
+		{"https://example.com/blog/index.html", "//cdn.example.com/app.js", "/site", "https://cdn.example.com/app.js"},
+		{"http://example.com/blog/index.html", "//cdn.example.com/app.js", "/site", "http://cdn.example.com/app.js"},
+		{"-", "/assets/app.js", "/site", "/assets/app.js"},
+	} {
+		got := resolveRef(tc.doc, tc.ref, tc.root)
+		if got != tc.want {
+			t.Errorf("resolveRef(%q, %q, %q) = %q, want %q", tc.doc, tc.ref, tc.root, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Philipp Stephani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phst/sri"
+)
+
+// entry is a single expected hash and the file or URL it applies to.
+type entry struct {
+	target string
+	want   sri.Hash
+}
+
+// runVerify verifies the given command-line entries plus, if manifest is
+// non-empty, the entries read from the manifest file ("-" for standard
+// input). If cacheDir is non-empty, downloaded URLs are served from and
+// stored in a content-addressable cache below cacheDir. It prints "OK" or
+// "FAILED" per entry to stdout and reports whether every entry matched.
+func runVerify(args []string, manifest, cacheDir string) bool {
+	var entries []entry
+	for _, a := range args {
+		e, err := parseVerifyArg(a)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sri: %s\n", err)
+			return false
+		}
+		entries = append(entries, e)
+	}
+	if manifest != "" {
+		m, err := readManifest(manifest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sri: %s\n", err)
+			return false
+		}
+		entries = append(entries, m...)
+	}
+
+	ok := true
+	for _, e := range entries {
+		if err := verifyEntry(e, cacheDir); err != nil {
+			fmt.Printf("%s: FAILED\n", e.target)
+			fmt.Fprintf(os.Stderr, "sri: %s: %s\n", e.target, err)
+			ok = false
+			continue
+		}
+		fmt.Printf("%s: OK\n", e.target)
+	}
+	return ok
+}
+
+// isURL reports whether target names an HTTP(S) URL rather than a local
+// file.
+func isURL(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+func verifyEntry(e entry, cacheDir string) error {
+	if cacheDir == "" || !isURL(e.target) {
+		r, err := sri.Open(e.target)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return e.want.Verify(r)
+	}
+
+	cachePath := e.want.CachePath(cacheDir)
+	if f, err := os.Open(cachePath); err == nil {
+		defer f.Close()
+		return e.want.Verify(f)
+	}
+	return fetchAndCache(e, cachePath)
+}
+
+// fetchAndCache downloads e.target, verifies it against e.want, and, only if
+// it matches, stores it at cachePath: it is written to a temporary file in
+// the same directory first and renamed into place afterwards, so a
+// concurrent reader never observes a partially written cache entry.
+func fetchAndCache(e entry, cachePath string) error {
+	r, err := sri.Open(e.target)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	dir := filepath.Dir(cachePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "sri-*.tmp")
+	if err != nil {
+		return err
+	}
+	removeTmp := true
+	defer func() {
+		if removeTmp {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w, err := sri.NewWriter(e.want.Algorithm)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	_, copyErr := io.Copy(io.MultiWriter(tmp, w), r)
+	if closeErr := tmp.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr != nil {
+		return copyErr
+	}
+	got := w.Sum()
+	if !got.Equal(e.want) {
+		return fmt.Errorf("integrity mismatch: want %s, got %s", e.want, got)
+	}
+	if err := os.Rename(tmp.Name(), cachePath); err != nil {
+		return err
+	}
+	removeTmp = false
+	return nil
+}
+
+// parseVerifyArg parses a command-line "path-or-url=sri" entry. Since both
+// the target (a URL query string may contain "=") and the sri (a base64
+// digest may end in "=" padding) can themselves contain "=", it doesn't
+// simply cut on the first or last occurrence: it tries every "=" from the
+// right and accepts the first split whose right-hand side parses as a valid
+// Subresource Integrity string.
+func parseVerifyArg(s string) (entry, error) {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] != '=' {
+			continue
+		}
+		target, sum := s[:i], s[i+1:]
+		if target == "" || sum == "" {
+			continue
+		}
+		if h, err := sri.Parse(sum); err == nil {
+			return entry{target: target, want: h}, nil
+		}
+	}
+	return entry{}, fmt.Errorf("malformed verify entry %q, want path-or-url=sri", s)
+}
+
+// readManifest reads entries from a manifest file in the tab-separated
+// "sri\tpath-or-url" format that sri itself prints. When sri was run with
+// several -hash algorithms, the first field is itself a space-separated
+// list of equivalent sri tokens for the same content; readManifest verifies
+// against the first one.
+func readManifest(name string) ([]entry, error) {
+	r, err := sri.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var entries []entry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sums, target, ok := strings.Cut(line, "\t")
+		if !ok || sums == "" || target == "" {
+			return nil, fmt.Errorf("malformed manifest line %q, want sri<TAB>path-or-url", line)
+		}
+		fields := strings.Fields(sums)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("malformed manifest line %q, want sri<TAB>path-or-url", line)
+		}
+		h, err := sri.Parse(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{target: target, want: h})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 Philipp Stephani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extra registers the additional hash algorithms "sha3-256",
+// "sha3-384", "sha3-512", "blake2b-256", and "blake2b-512" with package sri.
+// These algorithms aren't part of the Subresource Integrity specification,
+// but several tools produce and consume "algo-base64" strings for them
+// anyway. Blank-import this package to make the algorithms available to
+// sri.Parse, sri.NewWriter, and the -hash flag of the sri command:
+//
+//	import _ "github.com/phst/sri/extra"
+package extra
+
+import (
+	"crypto"
+
+	"github.com/phst/sri"
+
+	_ "golang.org/x/crypto/blake2b"
+	_ "golang.org/x/crypto/sha3"
+)
+
+func init() {
+	sri.Register("sha3-256", crypto.SHA3_256)
+	sri.Register("sha3-384", crypto.SHA3_384)
+	sri.Register("sha3-512", crypto.SHA3_512)
+	sri.Register("blake2b-256", crypto.BLAKE2b_256)
+	sri.Register("blake2b-512", crypto.BLAKE2b_512)
+}
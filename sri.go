@@ -12,115 +12,169 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Binary sri computes secure hashes in Subresource Integrity format for files or URLs.
-package main
+// Package sri computes and verifies cryptographic hashes in Subresource
+// Integrity format, as used in the "integrity" attribute of HTML script and
+// link elements.
+package sri
 
 import (
 	"crypto"
 	"encoding/base64"
-	"flag"
+	"encoding/hex"
 	"fmt"
-	"net/http"
+	"hash"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	_ "crypto/sha256"
 	_ "crypto/sha512"
 )
 
-func main() {
-	flag.Usage = usage
-	hashes := map[string]crypto.Hash{
+var (
+	registryMu sync.RWMutex
+	algorithms = map[string]crypto.Hash{
 		"sha256": crypto.SHA256,
 		"sha384": crypto.SHA384,
 		"sha512": crypto.SHA512,
 	}
-	var hashNames []string
-	for n := range hashes {
-		hashNames = append(hashNames, n)
+)
+
+// Register adds a hash algorithm under the given name, for use with Parse,
+// NewWriter and the -hash flag of the sri command. It is meant to be called
+// from the init function of a package that also arranges, typically via a
+// blank import, for h to be available (see crypto.Hash.Available). Register
+// panics if name is already registered; see package sri/extra for a
+// ready-made set of registrations.
+func Register(name string, h crypto.Hash) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := algorithms[name]; dup {
+		panic("sri: Register called twice for algorithm " + name)
 	}
-	sort.Strings(hashNames)
-	var hashName string
-	flag.StringVar(&hashName, "hash", "sha384", fmt.Sprintf("hash function to use (one of %v)", hashNames))
-	flag.Parse()
-	hash, ok := hashes[hashName]
-	if !ok {
-		fmt.Fprintf(os.Stderr, "sri: unknown hash function %s", hashName)
-		os.Exit(2)
+	algorithms[name] = h
+}
+
+// Algorithms returns the names of all supported hash algorithms, sorted
+// alphabetically.
+func Algorithms() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(algorithms))
+	for n := range algorithms {
+		names = append(names, n)
 	}
-	files := flag.Args()
-	if len(files) == 0 {
-		files = []string{"-"}
+	sort.Strings(names)
+	return names
+}
+
+func lookup(name string) (crypto.Hash, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	h, ok := algorithms[name]
+	return h, ok
+}
+
+// A Hash is a parsed Subresource Integrity string: an algorithm name paired
+// with a digest.
+type Hash struct {
+	Algorithm string
+	Digest    []byte
+}
+
+// Parse parses a Subresource Integrity string such as
+// "sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC".
+func Parse(s string) (Hash, error) {
+	algo, enc, ok := strings.Cut(s, "-")
+	if !ok {
+		return Hash{}, fmt.Errorf("malformed integrity string %q", s)
 	}
-	ch := make(chan result)
-	for _, f := range files {
-		go run(f, hash, ch)
+	if _, ok := lookup(algo); !ok {
+		return Hash{}, fmt.Errorf("unknown hash function %s", algo)
 	}
-	ok = true
-	suffix := len(files) > 1
-	for range files {
-		r := <- ch
-		if r.err != nil {
-			fmt.Fprintf(os.Stderr, "sri: %s: %s\n", r.file, r.err)
-			ok = false
-		}
-		var s strings.Builder
-		s.WriteString(hashName)
-		s.WriteByte('-')
-		s.WriteString(base64.StdEncoding.EncodeToString(r.hash))
-		if suffix {
-			s.WriteByte('\t')
-			s.WriteString(r.file)
-		}
-		s.WriteByte('\n')
-		if _, err := os.Stdout.WriteString(s.String()); err != nil {
-			fmt.Fprintf(os.Stderr, "sri: %s: %s", r.file, err)
-			ok = false
-		}
-	} 
-	if !ok {
-		os.Exit(1)
+	digest, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return Hash{}, fmt.Errorf("malformed integrity string %q: %s", s, err)
 	}
+	return Hash{Algorithm: algo, Digest: digest}, nil
 }
 
-func usage() {
-	os.Stderr.WriteString(`sri [options] [files and URLs...]
-
-Computes a cryptographic hash for each of the given files or HTTP URLs.
-For each file/URL, prints the hash in Subresource Integrity format,
-followed by a tab character, the filename/URL and a newline.
-If no files are given, reads standard input.
-A file named "-" is also interpreted to mean standard input.
-If zero or one positional arguments are given,
-print only the hash without a filename.
-`)
-	flag.PrintDefaults()
+// String returns the Subresource Integrity representation of h, e.g.
+// "sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC".
+func (h Hash) String() string {
+	var s strings.Builder
+	s.WriteString(h.Algorithm)
+	s.WriteByte('-')
+	s.WriteString(base64.StdEncoding.EncodeToString(h.Digest))
+	return s.String()
 }
 
-func run(f string, hash crypto.Hash, ch chan <-result) {
-	r, err := open(f)
+// Verify reads r to completion and reports whether its digest, computed
+// using h.Algorithm, matches h.Digest. It returns an error describing the
+// mismatch if not.
+func (h Hash) Verify(r io.Reader) error {
+	w, err := NewWriter(h.Algorithm)
 	if err != nil {
-		ch <- result{file: f, err: err}
-		return
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	}
+	got := w.Sum()
+	if !got.Equal(h) {
+		return fmt.Errorf("integrity mismatch: want %s, got %s", h, got)
 	}
-	defer r.Close()
-	h := hash.New()
-	if _, err := io.Copy(h, r); err != nil {
-		ch <- result{file: f, err: err}
-		return
+	return nil
+}
+
+// Equal reports whether h and other have the same algorithm and digest.
+func (h Hash) Equal(other Hash) bool {
+	return h.Algorithm == other.Algorithm && string(h.Digest) == string(other.Digest)
+}
+
+// CachePath returns the path at which a content-addressable cache rooted at
+// dir would store the data matching h: dir/algorithm/hex-digest. It uses
+// hexadecimal rather than h's usual base64 encoding so the result is safe to
+// use as a file name on all platforms.
+func (h Hash) CachePath(dir string) string {
+	return filepath.Join(dir, h.Algorithm, hex.EncodeToString(h.Digest))
+}
+
+// A Writer computes a Hash of the data written to it. The zero Writer is not
+// valid; use NewWriter to create one.
+type Writer struct {
+	algo string
+	hash hash.Hash
+}
+
+// NewWriter returns a Writer that computes a Hash using the named
+// algorithm. algo must be one of the names returned by Algorithms.
+func NewWriter(algo string) (*Writer, error) {
+	h, ok := lookup(algo)
+	if !ok {
+		return nil, fmt.Errorf("unknown hash function %s", algo)
 	}
-	ch <- result{file: f, hash: h.Sum(nil)}
+	return &Writer{algo: algo, hash: h.New()}, nil
+}
+
+// Write implements io.Writer, feeding p into the underlying hash function.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.hash.Write(p)
 }
 
-type result struct {
-	file string
-	err error
-	hash []byte
+// Sum returns the Hash of all data written to w so far.
+func (w *Writer) Sum() Hash {
+	return Hash{Algorithm: w.algo, Digest: w.hash.Sum(nil)}
 }
 
-func open(f string) (io.ReadCloser, error) {
+// Open opens f for reading. A name of "-" means standard input, and names
+// starting with "http://" or "https://" are fetched over HTTP; anything else
+// is interpreted as a local file name.
+func Open(f string) (io.ReadCloser, error) {
 	if f == "-" {
 		return io.NopCloser(os.Stdin), nil
 	}
@@ -136,4 +190,4 @@ func open(f string) (io.ReadCloser, error) {
 		return r.Body, nil
 	}
 	return os.Open(f)
-}
\ No newline at end of file
+}
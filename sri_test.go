@@ -0,0 +1,85 @@
+// Copyright 2023 Philipp Stephani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sri
+
+import (
+	"crypto"
+	"strings"
+	"testing"
+)
+
+func TestParseString(t *testing.T) {
+	const s = "sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC"
+	h, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q) = %v", s, err)
+	}
+	if h.Algorithm != "sha384" {
+		t.Errorf("Parse(%q).Algorithm = %q, want sha384", s, h.Algorithm)
+	}
+	if got := h.String(); got != s {
+		t.Errorf("round trip: String() = %q, want %q", got, s)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"nodash",
+		"sha999-AAAA",
+		"sha256-not base64!",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const data = "hello, world"
+	w, err := NewWriter("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	h := w.Sum()
+
+	if err := h.Verify(strings.NewReader(data)); err != nil {
+		t.Errorf("Verify with matching data: %v", err)
+	}
+	if err := h.Verify(strings.NewReader("something else")); err == nil {
+		t.Error("Verify with mismatching data succeeded, want error")
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register with a duplicate name did not panic")
+		}
+	}()
+	Register("sha256", crypto.SHA256)
+}
+
+func TestCachePath(t *testing.T) {
+	h := Hash{Algorithm: "sha256", Digest: []byte{0xAB, 0xCD}}
+	got := h.CachePath("/cache")
+	want := "/cache/sha256/abcd"
+	if got != want {
+		t.Errorf("CachePath() = %q, want %q", got, want)
+	}
+}